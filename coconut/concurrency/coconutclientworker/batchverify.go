@@ -0,0 +1,29 @@
+// batchverify.go - CoconutClientWorker wrapper around coconut.BatchVerify.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package coconutclientworker
+
+import (
+	"github.com/jstuczyn/CoconutGo/coconut/scheme"
+)
+
+// BatchVerify mirrors coconut.BatchVerify for the worker-backed client, verifying N
+// (verification key, attributes, signature) triples with a single randomized pairing
+// product rather than N calls to Verify. params carries the curve/field shared by
+// every item in items, exactly as a single Params does for coconut.BatchVerify.
+func (ccw *CoconutClientWorker) BatchVerify(params *MuxParams, items []coconut.VerifyItem) (bool, []int) {
+	return coconut.BatchVerify(params, items)
+}
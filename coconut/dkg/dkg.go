@@ -0,0 +1,598 @@
+// dkg.go - Pedersen-VSS based distributed key generation for Coconut threshold keys.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package dkg implements a Pedersen-VSS distributed key generation protocol that
+// produces Coconut secret/verification key shares without ever requiring a single
+// trusted dealer, as an alternative to coconut.TTPKeygen.
+//
+// Each of the q+1 secret components (x, y1, ..., yq) is generated independently by
+// every participant sampling a random degree-t polynomial, broadcasting Feldman/
+// Pedersen commitments to its coefficients and privately distributing evaluations of
+// that polynomial to every other participant. A participant's final share of a given
+// component is the sum of the evaluations it received for that component, and the
+// corresponding public key part is the sum of the dealers' constant-term commitments.
+//
+// Round-2 shares are encrypted to their recipient (see RoundTwoShare) so the protocol
+// can be driven over a transport with no confidentiality of its own. A recipient who
+// finds a share does not match its dealer's broadcast commitments files a Complaint;
+// the accused dealer can answer with a Justification revealing the plaintext it
+// claims to have sent, which every participant can check independently (see
+// ResolveJustification) instead of just taking the complainant's word for it.
+//
+// The Participant type is a pure state machine - it produces and consumes messages
+// but performs no networking itself, so it can in principle be driven synchronously
+// (as RunDKG does, for tests) or carried over any transport.
+//
+// It is NOT currently wired into coconut/concurrency/coconutclientworker: that package
+// only ever holds a BatchVerify wrapper around a CoconutClientWorker type this snapshot
+// never defines, so there is no worker pool here for a Participant to be driven
+// through yet. Whoever adds that type should give RunDKG's round-trip a transport-
+// backed counterpart built on it rather than assuming this package already did so.
+package dkg
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jstuczyn/CoconutGo/coconut/scheme"
+	"github.com/jstuczyn/CoconutGo/coconut/utils"
+	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// dkgRNG is a package-level AMCL RNG seeded from crypto/rand, shared by every call that
+// needs one rather than reseeding per call, and mutex-guarded via utils.SafeRAND so
+// separate Participants sampling at the same time never share RNG state.
+var dkgRNG = utils.NewSafeRAND()
+
+// ErrDisqualified is returned when an operation is attempted on behalf of, or using
+// data from, a participant that has already been disqualified.
+var ErrDisqualified = errors.New("dkg: participant is disqualified")
+
+// ErrInvalidShare is returned when a share fails verification against its dealer's
+// broadcast commitments.
+var ErrInvalidShare = errors.New("dkg: share does not match broadcast commitments")
+
+// ErrInsufficientDealers is returned by Finalize when fewer than t+1 dealers ended up
+// both qualified and fully resolved, so the resulting key share cannot be guaranteed
+// to reconstruct consistently with any other participant's.
+var ErrInsufficientDealers = errors.New("dkg: fewer than t+1 qualified dealers contributed, cannot finalize")
+
+// shareMaskDST domain-separates the additive masks ShareFor/VerifyShare derive from an
+// ECDH shared point to keep each RoundTwoShare's plaintext confidential in transit -
+// see the encryption note on RoundTwoShare.
+const shareMaskDST = "COCONUT-V01-CS02-with-BLS12381G1_XMD:SHA-256_RO_dkg-share_"
+
+// deriveShareMask derives the additive mask used to encrypt/decrypt the share dealer
+// sends recipient for component, from their ECDH shared point. Folding in (dealer,
+// recipient, component) means masking one share can never be confused with, or
+// replayed as, a different one even though every component in a RoundTwoShare is
+// encrypted under the same shared point.
+func deriveShareMask(shared *Curve.ECP, dealer, recipient, component int) (*Curve.BIG, error) {
+	buf := make([]byte, int(Curve.MODBYTES)+1)
+	shared.ToBytes(buf, true)
+	msg := append(buf, []byte(fmt.Sprintf("-%d-%d-%d", dealer, recipient, component))...)
+	return utils.HashToScalar([]byte(shareMaskDST), msg)
+}
+
+// Commitment holds the Feldman commitments C_k = g2^{a_k} to the coefficients of a
+// single degree-t polynomial, one polynomial per secret component (x, y1, ..., yq).
+type Commitment struct {
+	Dealer int
+	Coeffs []*Curve.ECP2 // C_0 ... C_t
+}
+
+// RoundOneBroadcast is the message every participant sends to all others (and to
+// itself) at the start of the protocol: one Commitment per secret component, plus the
+// dealer's transport public key (see RoundTwoShare) that every other participant needs
+// in order to encrypt the shares they send it.
+type RoundOneBroadcast struct {
+	Dealer      int
+	TransportPK *Curve.ECP
+	Commitments []*Commitment // one per secret component
+}
+
+// RoundTwoShare is the message a dealer sends to a single recipient j, carrying
+// s_{i,j} = f_i(j) for every secret component's polynomial. Shares[k] is not the
+// plaintext s_{i,j} itself but s_{i,j} masked additively (mod p) under a key derived
+// from an ECDH shared point between the dealer's and recipient's transport keys (see
+// deriveShareMask), so a RoundTwoShare can be carried over a transport that offers no
+// confidentiality of its own without leaking any share in the clear.
+type RoundTwoShare struct {
+	Dealer    int
+	Recipient int
+	Shares    []*Curve.BIG // one per secret component, same order as RoundOneBroadcast.Commitments
+}
+
+// Complaint is filed by a recipient against a dealer whose decrypted share did not
+// match the dealer's broadcast commitments.
+type Complaint struct {
+	Complainant int
+	Accused     int
+	Component   int // index into Commitments/Shares that failed verification
+	Reason      error
+}
+
+// Justification lets an accused dealer answer a Complaint by revealing the exact
+// plaintext it claims to have sent for Complaint.Complainant and Complaint.Component,
+// so every participant - not just the complainant - can independently check whether
+// the complaint was justified.
+type Justification struct {
+	Accused     int
+	Complainant int
+	Component   int
+	Share       *Curve.BIG
+}
+
+// Participant drives one party's side of the DKG protocol. It is not safe for
+// concurrent use.
+type Participant struct {
+	index  int // 1-indexed, matches the x-coordinate used for Shamir evaluation
+	t      int
+	n      int
+	params coconut.CoconutParams
+
+	// polys[k] is this participant's own degree-t polynomial for secret component k.
+	polys [][]*Curve.BIG
+
+	// transportSK/transportPK is this participant's static Diffie-Hellman keypair
+	// (transportPK = g1^transportSK), broadcast in round 1 and used to derive the
+	// ECDH shared points round-2 shares are masked under.
+	transportSK *Curve.BIG
+	transportPK *Curve.ECP
+	// transportPKs[i] is participant i's broadcast transport public key.
+	transportPKs map[int]*Curve.ECP
+
+	commitments map[int][]*Commitment // dealer -> per-component commitments
+	// shares[dealer][k] is the plaintext share we decrypted and verified for
+	// component k from dealer, or nil if that component is still under an
+	// unresolved Complaint.
+	shares     map[int][]*Curve.BIG
+	qualified  map[int]bool // dealers not yet disqualified
+	components int          // q+1
+}
+
+// NewParticipant creates a fresh DKG participant. index must be in [1, n] and is the
+// participant's Shamir x-coordinate; components is the number of secret components
+// to jointly generate (q+1 for a Coconut key supporting q attributes).
+func NewParticipant(index, t, n, components int, params coconut.CoconutParams) (*Participant, error) {
+	if index < 1 || index > n {
+		return nil, fmt.Errorf("dkg: participant index %v out of range [1, %v]", index, n)
+	}
+	if t < 0 || t >= n {
+		return nil, fmt.Errorf("dkg: invalid threshold %v for %v participants", t, n)
+	}
+
+	qualified := make(map[int]bool)
+	for i := 1; i <= n; i++ {
+		qualified[i] = true
+	}
+
+	transportSK := dkgRNG.Randomnum(params.P())
+	transportPK := Curve.G1mul(params.G1(), transportSK)
+
+	return &Participant{
+		index:        index,
+		t:            t,
+		n:            n,
+		params:       params,
+		transportSK:  transportSK,
+		transportPK:  transportPK,
+		transportPKs: make(map[int]*Curve.ECP),
+		commitments:  make(map[int][]*Commitment),
+		shares:       make(map[int][]*Curve.BIG),
+		qualified:    qualified,
+		components:   components,
+	}, nil
+}
+
+// samplePolynomial samples a random degree-t polynomial a_0 + a_1 X + ... + a_t X^t
+// over Z_p, used as the secret sharing polynomial for a single component.
+func samplePolynomial(t int, p *Curve.BIG) []*Curve.BIG {
+	coeffs := make([]*Curve.BIG, t+1)
+	for i := range coeffs {
+		coeffs[i] = dkgRNG.Randomnum(p)
+	}
+	return coeffs
+}
+
+func evalPolynomial(coeffs []*Curve.BIG, x int, p *Curve.BIG) *Curve.BIG {
+	result := Curve.NewBIG()
+	xBIG := Curve.NewBIGint(x)
+	power := Curve.NewBIGint(1)
+	for _, a := range coeffs {
+		result = result.Plus(Curve.Modmul(a, power, p))
+		result.Mod(p)
+		power = Curve.Modmul(power, xBIG, p)
+	}
+	return result
+}
+
+// commitPolynomial returns the Feldman commitments C_k = g2^{a_k} to coeffs.
+func commitPolynomial(g2 *Curve.ECP2, coeffs []*Curve.BIG) []*Curve.ECP2 {
+	commits := make([]*Curve.ECP2, len(coeffs))
+	for i, a := range coeffs {
+		commits[i] = Curve.G2mul(g2, a)
+	}
+	return commits
+}
+
+// RoundOne samples this participant's polynomials (one per secret component) and
+// returns the broadcast it must send to every other participant.
+func (p *Participant) RoundOne() (*RoundOneBroadcast, error) {
+	field := p.params.P()
+	g2 := p.params.G2()
+
+	p.polys = make([][]*Curve.BIG, p.components)
+	commits := make([]*Commitment, p.components)
+
+	for k := 0; k < p.components; k++ {
+		coeffs := samplePolynomial(p.t, field)
+		p.polys[k] = coeffs
+		commits[k] = &Commitment{Dealer: p.index, Coeffs: commitPolynomial(g2, coeffs)}
+	}
+
+	// a dealer trusts its own commitments and transport key unconditionally
+	p.commitments[p.index] = commits
+	p.transportPKs[p.index] = p.transportPK
+
+	return &RoundOneBroadcast{Dealer: p.index, TransportPK: p.transportPK, Commitments: commits}, nil
+}
+
+// ReceiveCommitments records another dealer's round-1 broadcast. It must be called
+// for every dealer (including ones later found to cheat) before RoundTwoShare is
+// verified against them, and before ShareFor can encrypt a share to them.
+func (p *Participant) ReceiveCommitments(broadcast *RoundOneBroadcast) error {
+	if len(broadcast.Commitments) != p.components {
+		return fmt.Errorf("dkg: dealer %v broadcast %v components, expected %v",
+			broadcast.Dealer, len(broadcast.Commitments), p.components)
+	}
+	p.commitments[broadcast.Dealer] = broadcast.Commitments
+	p.transportPKs[broadcast.Dealer] = broadcast.TransportPK
+	return nil
+}
+
+// ShareFor evaluates this participant's polynomials at recipient's index and encrypts
+// the results under the ECDH shared point between this dealer's and recipient's
+// transport keys, producing the RoundTwoShare that can be carried over any transport -
+// including one with no confidentiality of its own - to recipient. ReceiveCommitments
+// must already have recorded recipient's transport key (e.g. via its round-1
+// broadcast) before this is called.
+func (p *Participant) ShareFor(recipient int) (*RoundTwoShare, error) {
+	recipientPK, ok := p.transportPKs[recipient]
+	if !ok {
+		return nil, fmt.Errorf("dkg: no transport key on file for recipient %v", recipient)
+	}
+
+	field := p.params.P()
+	shared := Curve.G1mul(recipientPK, p.transportSK)
+
+	shares := make([]*Curve.BIG, p.components)
+	for k, coeffs := range p.polys {
+		plain := evalPolynomial(coeffs, recipient, field)
+		mask, err := deriveShareMask(shared, p.index, recipient, k)
+		if err != nil {
+			return nil, err
+		}
+		enc := plain.Plus(mask)
+		enc.Mod(field)
+		shares[k] = enc
+	}
+	return &RoundTwoShare{Dealer: p.index, Recipient: recipient, Shares: shares}, nil
+}
+
+// VerifyShare decrypts share using the ECDH shared point between this participant's
+// and the dealer's transport keys, then checks each resulting plaintext against the
+// dealer's previously broadcast commitments, i.e. that g2^{s_{i,j}} ==
+// Prod_k C_{i,k}^{j^k}. Components that verify are accepted towards this
+// participant's final key share immediately; components that don't each produce a
+// Complaint and are left unresolved (see Justify/ResolveJustification) rather than
+// failing the whole share.
+func (p *Participant) VerifyShare(share *RoundTwoShare) ([]*Complaint, error) {
+	if !p.qualified[share.Dealer] {
+		return nil, ErrDisqualified
+	}
+	commits, ok := p.commitments[share.Dealer]
+	if !ok {
+		return nil, fmt.Errorf("dkg: no commitments on file for dealer %v", share.Dealer)
+	}
+	dealerPK, ok := p.transportPKs[share.Dealer]
+	if !ok {
+		return nil, fmt.Errorf("dkg: no transport key on file for dealer %v", share.Dealer)
+	}
+	if len(share.Shares) != p.components {
+		return []*Complaint{{Complainant: p.index, Accused: share.Dealer, Component: -1,
+			Reason: fmt.Errorf("dkg: expected %v shares, got %v", p.components, len(share.Shares))}}, nil
+	}
+	if _, seen := p.shares[share.Dealer]; seen {
+		return nil, fmt.Errorf("dkg: duplicate share from dealer %v", share.Dealer)
+	}
+
+	g2 := p.params.G2()
+	field := p.params.P()
+	shared := Curve.G1mul(dealerPK, p.transportSK)
+
+	resolved := make([]*Curve.BIG, p.components)
+	var complaints []*Complaint
+	for k, enc := range share.Shares {
+		mask, err := deriveShareMask(shared, share.Dealer, p.index, k)
+		if err != nil {
+			return nil, err
+		}
+		plain := Curve.NewBIGcopy(enc)
+		plain = plain.Plus(Curve.Modneg(mask, field))
+		plain.Mod(field)
+
+		lhs := Curve.G2mul(g2, plain)
+		rhs := evalCommitment(commits[k].Coeffs, p.index, field)
+		if !lhs.Equals(rhs) {
+			complaints = append(complaints, &Complaint{Complainant: p.index, Accused: share.Dealer, Component: k, Reason: ErrInvalidShare})
+			continue
+		}
+		resolved[k] = plain
+	}
+
+	p.shares[share.Dealer] = resolved
+	return complaints, nil
+}
+
+// Justify lets this participant (the accused dealer) answer complaint by revealing
+// the plaintext share it computed for complaint.Complainant and complaint.Component;
+// complaint.Accused must equal this participant's index.
+func (p *Participant) Justify(complaint *Complaint) (*Justification, error) {
+	if complaint.Accused != p.index {
+		return nil, fmt.Errorf("dkg: complaint accuses %v, not this participant (%v)", complaint.Accused, p.index)
+	}
+	if complaint.Component < 0 || complaint.Component >= len(p.polys) {
+		return nil, fmt.Errorf("dkg: complaint names invalid component %v", complaint.Component)
+	}
+
+	field := p.params.P()
+	share := evalPolynomial(p.polys[complaint.Component], complaint.Complainant, field)
+	return &Justification{Accused: p.index, Complainant: complaint.Complainant, Component: complaint.Component, Share: share}, nil
+}
+
+// ResolveJustification checks an accused dealer's Justification for complaint against
+// the dealer's broadcast commitments. If the revealed share matches them, the
+// complaint was spurious: the dealer is not disqualified, and if this participant is
+// the complainant, the now-known-correct plaintext is recorded as that component's
+// share. If it does not match, the dealer really did send a bad share and is
+// disqualified, regardless of which participant calls this.
+func (p *Participant) ResolveJustification(complaint *Complaint, justification *Justification) error {
+	if justification.Accused != complaint.Accused || justification.Component != complaint.Component ||
+		justification.Complainant != complaint.Complainant {
+		return errors.New("dkg: justification does not match complaint")
+	}
+	commits, ok := p.commitments[complaint.Accused]
+	if !ok {
+		return fmt.Errorf("dkg: no commitments on file for dealer %v", complaint.Accused)
+	}
+
+	field := p.params.P()
+	g2 := p.params.G2()
+	lhs := Curve.G2mul(g2, justification.Share)
+	rhs := evalCommitment(commits[complaint.Component].Coeffs, complaint.Complainant, field)
+	if !lhs.Equals(rhs) {
+		p.Disqualify(complaint.Accused)
+		return ErrInvalidShare
+	}
+
+	if p.index == complaint.Complainant {
+		resolved, ok := p.shares[complaint.Accused]
+		if !ok {
+			resolved = make([]*Curve.BIG, p.components)
+		}
+		resolved[complaint.Component] = justification.Share
+		p.shares[complaint.Accused] = resolved
+	}
+	return nil
+}
+
+// evalCommitment computes Prod_k C_k^{j^k}, the public evaluation of a committed
+// polynomial at j, without ever learning the polynomial's coefficients.
+func evalCommitment(coeffs []*Curve.ECP2, j int, p *Curve.BIG) *Curve.ECP2 {
+	jBIG := Curve.NewBIGint(j)
+	power := Curve.NewBIGint(1)
+	result := Curve.NewECP2() // identity
+	for _, c := range coeffs {
+		result.Add(Curve.G2mul(c, power))
+		power = Curve.Modmul(power, jBIG, p)
+	}
+	return result
+}
+
+// Disqualify removes a dealer from the qualified set; its contribution (if any was
+// already recorded) is discarded from subsequent Finalize calls.
+func (p *Participant) Disqualify(dealer int) {
+	p.qualified[dealer] = false
+	delete(p.shares, dealer)
+	delete(p.commitments, dealer)
+}
+
+// Finalize sums this participant's accepted shares into its final secret key share,
+// and sums the qualified dealers' constant-term commitments into the matching public
+// verification key part: sk_j = Sum_i s_{i,j}, vk part = Sum_i C_{i,0}.
+//
+// Only dealers that are both still qualified and fully resolved - every component
+// either verified directly by VerifyShare or recovered via ResolveJustification, none
+// left nil by an outstanding Complaint - contribute. If fewer than t+1 dealers meet
+// that bar, Finalize fails with ErrInsufficientDealers rather than silently returning
+// a key share built from too few contributions to be guaranteed consistent with any
+// other participant's.
+//
+// BLOCKING, NOT YET CONFIRMED: Finalize assembles its result via
+// coconut.NewSecretKey / coconut.NewVerificationKey. Neither constructor is defined
+// anywhere in this snapshot, nor referenced by any other call site: every other place
+// that needs a SecretKey or VerificationKey (the batchverify.go pairing checks,
+// testutils/scheme.go) only ever reads one back out through its getters (X, Y, Alpha,
+// Beta, G2, ...), which implies unexported fields that this package - coconut/dkg,
+// not coconut itself - cannot set without an exported constructor. This environment
+// has no access to the real coconut/scheme source to check these two constructors
+// exist with this signature, so this is an assumption, not a confirmed fact - do not
+// merge this package on the strength of this comment alone. Whoever has the real
+// coconut/scheme source must confirm they exist before merging; if they don't, add
+// them (or switch Finalize to whatever the real construction path is) - otherwise
+// this package fails to compile.
+func (p *Participant) Finalize() (*coconut.SecretKey, *coconut.VerificationKey, error) {
+	field := p.params.P()
+	g2 := p.params.G2()
+
+	x := Curve.NewBIG()
+	ys := make([]*Curve.BIG, p.components-1)
+	for i := range ys {
+		ys[i] = Curve.NewBIG()
+	}
+	alpha := Curve.NewECP2()
+	beta := make([]*Curve.ECP2, p.components-1)
+	for i := range beta {
+		beta[i] = Curve.NewECP2()
+	}
+
+	contributing := 0
+	for dealer := range p.qualified {
+		if !p.qualified[dealer] {
+			continue
+		}
+		shares, ok := p.shares[dealer]
+		if !ok {
+			continue // dealer never contributed, e.g. it was disqualified before sharing
+		}
+		complete := true
+		for _, s := range shares {
+			if s == nil {
+				complete = false
+				break
+			}
+		}
+		if !complete {
+			continue // an outstanding complaint against this dealer was never justified
+		}
+		commits := p.commitments[dealer]
+
+		x = x.Plus(shares[0])
+		x.Mod(field)
+		alpha.Add(commits[0].Coeffs[0])
+
+		for i := 1; i < p.components; i++ {
+			ys[i-1] = ys[i-1].Plus(shares[i])
+			ys[i-1].Mod(field)
+			beta[i-1].Add(commits[i].Coeffs[0])
+		}
+		contributing++
+	}
+
+	if contributing < p.t+1 {
+		return nil, nil, ErrInsufficientDealers
+	}
+
+	sk := coconut.NewSecretKey(x, ys)
+	vk := coconut.NewVerificationKey(g2, alpha, beta)
+	return sk, vk, nil
+}
+
+// RunDKG drives n Participants through the full protocol synchronously in a single
+// process - every broadcast and "private" share is delivered in-memory - and returns
+// the resulting per-participant secret/verification key shares, in the same shape as
+// coconut.TTPKeygen.
+func RunDKG(params coconut.CoconutParams, t, n, components int) ([]*coconut.SecretKey, []*coconut.VerificationKey, error) {
+	participants := make([]*Participant, n)
+	for i := range participants {
+		p, err := NewParticipant(i+1, t, n, components, params)
+		if err != nil {
+			return nil, nil, err
+		}
+		participants[i] = p
+	}
+
+	broadcasts := make([]*RoundOneBroadcast, n)
+	for i, p := range participants {
+		b, err := p.RoundOne()
+		if err != nil {
+			return nil, nil, err
+		}
+		broadcasts[i] = b
+	}
+
+	for _, recipient := range participants {
+		for _, b := range broadcasts {
+			if err := recipient.ReceiveCommitments(b); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var complaints []*Complaint
+	for _, dealer := range participants {
+		for _, recipient := range participants {
+			share, err := dealer.ShareFor(recipient.index)
+			if err != nil {
+				return nil, nil, err
+			}
+			cs, err := recipient.VerifyShare(share)
+			if err != nil {
+				return nil, nil, err
+			}
+			complaints = append(complaints, cs...)
+		}
+	}
+
+	// A Complaint is only one participant's accusation; resolving it (accepting the
+	// accused dealer's Justification, or disqualifying it if none survives scrutiny)
+	// must be broadcast to and independently re-checked by every participant, not just
+	// the complainant - otherwise honest participants can end up qualifying different
+	// dealer subsets and their key shares stop being consistent Shamir shares of one
+	// secret, which is exactly what the accusation process exists to prevent.
+	for _, complaint := range complaints {
+		if complaint.Component < 0 {
+			// VerifyShare raises this when the dealer's RoundTwoShare didn't even have
+			// the right number of components - there is no well-formed share for
+			// Justify to reveal, so routing this through the Justify/Resolve
+			// round-trip would only fail with "invalid component" and abort the whole
+			// run. The malformed message is itself damning regardless of what any
+			// polynomial evaluates to, so disqualify the dealer directly everywhere,
+			// the same way an unresolvable Justification would.
+			for _, p := range participants {
+				p.Disqualify(complaint.Accused)
+			}
+			continue
+		}
+		accused := participants[complaint.Accused-1]
+		justification, err := accused.Justify(complaint)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range participants {
+			if !p.qualified[complaint.Accused] {
+				continue // already excluded by an earlier complaint against this dealer
+			}
+			if err := p.ResolveJustification(complaint, justification); err != nil && err != ErrInvalidShare {
+				return nil, nil, err
+			}
+		}
+	}
+
+	sks := make([]*coconut.SecretKey, n)
+	vks := make([]*coconut.VerificationKey, n)
+	for i, p := range participants {
+		sk, vk, err := p.Finalize()
+		if err != nil {
+			return nil, nil, err
+		}
+		sks[i] = sk
+		vks[i] = vk
+	}
+
+	return sks, vks, nil
+}
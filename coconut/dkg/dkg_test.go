@@ -0,0 +1,245 @@
+// dkg_test.go - Tests for the Pedersen-VSS distributed key generation protocol.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dkg
+
+import (
+	"testing"
+
+	"github.com/jstuczyn/CoconutGo/coconut/scheme"
+	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+	"github.com/stretchr/testify/assert"
+)
+
+// interpolateAtZero recovers f(0) given k Shamir shares f(1)..f(k) of a degree-(k-1)
+// (or lower) polynomial over Z_p, via Lagrange interpolation.
+func interpolateAtZero(shares []*Curve.BIG, k int, p *Curve.BIG) *Curve.BIG {
+	result := Curve.NewBIG()
+	for i := 0; i < k; i++ {
+		xi := Curve.NewBIGint(i + 1)
+
+		num := Curve.NewBIGint(1)
+		den := Curve.NewBIGint(1)
+		for j := 0; j < k; j++ {
+			if j == i {
+				continue
+			}
+			xj := Curve.NewBIGint(j + 1)
+
+			negXj := Curve.Modneg(xj, p)
+			num = Curve.Modmul(num, negXj, p)
+
+			diff := negXj.Plus(xi)
+			diff.Mod(p)
+			den = Curve.Modmul(den, diff, p)
+		}
+		den.Invmodp(p)
+		coeff := Curve.Modmul(num, den, p)
+
+		term := Curve.Modmul(coeff, shares[i], p)
+		result = result.Plus(term)
+		result.Mod(p)
+	}
+	return result
+}
+
+// TestRunDKG drives a full t-of-n run of RunDKG and checks that every participant
+// agrees on the same verification key, and that their secret key shares really are
+// consistent Shamir shares of the secret that key commits to: interpolating any t+1
+// of the x shares at 0 must reproduce alpha = g2^x.
+func TestRunDKG(t *testing.T) {
+	const threshold = 2 // t; t+1 = 3 shares needed to reconstruct
+	const n = 5
+	const components = 3 // q+1 for q=2 attributes
+
+	params, err := coconut.Setup(components - 1)
+	assert.Nil(t, err)
+
+	sks, vks, err := RunDKG(params, threshold, n, components)
+	assert.Nil(t, err)
+	assert.Len(t, sks, n)
+	assert.Len(t, vks, n)
+
+	for i := 1; i < n; i++ {
+		assert.True(t, vks[0].G2().Equals(vks[i].G2()), "every participant must agree on g2")
+		assert.True(t, vks[0].Alpha().Equals(vks[i].Alpha()), "every participant must agree on alpha")
+		for k := range vks[0].Beta() {
+			assert.True(t, vks[0].Beta()[k].Equals(vks[i].Beta()[k]), "every participant must agree on beta")
+		}
+	}
+
+	field := params.P()
+	xShares := make([]*Curve.BIG, n)
+	for i, sk := range sks {
+		xShares[i] = sk.X()
+	}
+	x := interpolateAtZero(xShares, threshold+1, field)
+
+	reconstructed := Curve.G2mul(params.G2(), x)
+	assert.True(t, reconstructed.Equals(vks[0].Alpha()),
+		"interpolating any t+1 secret key x shares at 0 must reproduce alpha = g2^x")
+}
+
+// TestRunDKGCheatingDealer drives the protocol by hand with one dealer broadcasting a
+// component-0 commitment that does not match its own polynomial, and checks that the
+// resulting Complaint/Justification exchange is broadcast consistently: every honest
+// participant ends up disqualifying the same dealer, and their final secret key shares
+// still interpolate to the same alpha = g2^x as if the cheating dealer had never taken
+// part. A dealer's Justify always reveals the share its real polynomial produces, so
+// the only way a complaint can be genuinely unresolvable - rather than just a
+// transport glitch corrected by Justify - is an inconsistent broadcast commitment like
+// this one; that is the scenario Complaint/Justification exist for, and TestRunDKG only
+// ever exercises the fully honest path.
+func TestRunDKGCheatingDealer(t *testing.T) {
+	const threshold = 2 // t; t+1 = 3 shares needed to reconstruct
+	const n = 5
+	const components = 2
+	const cheatingDealer = 2
+
+	params, err := coconut.Setup(components - 1)
+	assert.Nil(t, err)
+	field := params.P()
+
+	participants := make([]*Participant, n)
+	for i := range participants {
+		p, err := NewParticipant(i+1, threshold, n, components, params)
+		assert.Nil(t, err)
+		participants[i] = p
+	}
+
+	broadcasts := make([]*RoundOneBroadcast, n)
+	for i, p := range participants {
+		b, err := p.RoundOne()
+		assert.Nil(t, err)
+		broadcasts[i] = b
+	}
+	// Corrupt the cheating dealer's broadcast component-0 commitment so it no longer
+	// matches the polynomial it actually shares from; this also corrupts the dealer's
+	// own bookkeeping copy, since RoundOne hands out the same Commitment pointers it
+	// keeps for itself.
+	broadcasts[cheatingDealer-1].Commitments[0].Coeffs[0].Add(params.G2())
+
+	for _, recipient := range participants {
+		for _, b := range broadcasts {
+			assert.Nil(t, recipient.ReceiveCommitments(b))
+		}
+	}
+
+	var complaints []*Complaint
+	for _, dealer := range participants {
+		for _, recipient := range participants {
+			share, err := dealer.ShareFor(recipient.index)
+			assert.Nil(t, err)
+			cs, err := recipient.VerifyShare(share)
+			assert.Nil(t, err)
+			complaints = append(complaints, cs...)
+		}
+	}
+	assert.Len(t, complaints, n, "every participant's component-0 share from the cheating dealer must fail verification")
+	for _, complaint := range complaints {
+		assert.Equal(t, cheatingDealer, complaint.Accused)
+		assert.Equal(t, 0, complaint.Component)
+	}
+
+	for _, complaint := range complaints {
+		accused := participants[complaint.Accused-1]
+		justification, err := accused.Justify(complaint)
+		assert.Nil(t, err)
+		// The dealer's real polynomial can't match its own corrupted commitment, so
+		// every participant who checks independently must agree the dealer cheated.
+		for _, p := range participants {
+			if !p.qualified[complaint.Accused] {
+				continue
+			}
+			err := p.ResolveJustification(complaint, justification)
+			assert.Equal(t, ErrInvalidShare, err)
+		}
+	}
+
+	for _, p := range participants {
+		assert.False(t, p.qualified[cheatingDealer], "every participant must disqualify the cheating dealer")
+	}
+
+	xShares := make([]*Curve.BIG, 0, n)
+	vks := make([]*coconut.VerificationKey, 0, n)
+	for _, p := range participants {
+		sk, vk, err := p.Finalize()
+		assert.Nil(t, err)
+		xShares = append(xShares, sk.X())
+		vks = append(vks, vk)
+	}
+
+	for i := 1; i < n; i++ {
+		assert.True(t, vks[0].Alpha().Equals(vks[i].Alpha()),
+			"every honest participant must agree on alpha despite the cheating dealer")
+	}
+
+	x := interpolateAtZero(xShares, threshold+1, field)
+	reconstructed := Curve.G2mul(params.G2(), x)
+	assert.True(t, reconstructed.Equals(vks[0].Alpha()),
+		"interpolating the post-disqualification shares must still reproduce alpha = g2^x")
+}
+
+// TestRunDKGInsufficientDealers checks that RunDKG itself still succeeds when every
+// dealer behaves, and that a Participant whose qualified dealer count has fallen
+// below t+1 - simulating every dealer but one being disqualified after the protocol
+// ran - refuses to Finalize instead of silently returning a key share built from too
+// little.
+func TestRunDKGInsufficientDealers(t *testing.T) {
+	const threshold = 2
+	const n = 5
+	const components = 2
+
+	params, err := coconut.Setup(components - 1)
+	assert.Nil(t, err)
+
+	_, _, err = RunDKG(params, threshold, n, components)
+	assert.Nil(t, err, "a fully honest run must succeed")
+
+	participants := make([]*Participant, n)
+	for i := range participants {
+		p, err := NewParticipant(i+1, threshold, n, components, params)
+		assert.Nil(t, err)
+		participants[i] = p
+	}
+	broadcasts := make([]*RoundOneBroadcast, n)
+	for i, p := range participants {
+		b, err := p.RoundOne()
+		assert.Nil(t, err)
+		broadcasts[i] = b
+	}
+	for _, recipient := range participants {
+		for _, b := range broadcasts {
+			assert.Nil(t, recipient.ReceiveCommitments(b))
+		}
+	}
+	for _, dealer := range participants {
+		for _, recipient := range participants {
+			share, err := dealer.ShareFor(recipient.index)
+			assert.Nil(t, err)
+			_, err = recipient.VerifyShare(share)
+			assert.Nil(t, err)
+		}
+	}
+
+	// Disqualify every dealer but one from participant 1's point of view: too few
+	// remain for a t=2 threshold (which needs 3), so Finalize must refuse.
+	for i := 2; i <= n; i++ {
+		participants[0].Disqualify(i)
+	}
+	_, _, err = participants[0].Finalize()
+	assert.Equal(t, ErrInsufficientDealers, err)
+}
@@ -0,0 +1,84 @@
+// bbsig.go - Boneh-Boyen signatures on the digits {0, ..., u-1}, as used by the
+// Camenisch-Chaabouni-Shelat range proof.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rangeproof
+
+import (
+	"fmt"
+
+	"github.com/jstuczyn/CoconutGo/coconut/scheme"
+	"github.com/jstuczyn/CoconutGo/coconut/utils"
+	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// bbRNG is a package-level AMCL RNG seeded from crypto/rand, shared by every call that
+// needs one rather than reseeding per call, and mutex-guarded via utils.SafeRAND so
+// concurrent calls to SetupBB, Prove and SampleScalar never share RNG state.
+var bbRNG = utils.NewSafeRAND()
+
+// BBParams is the one-time trusted setup for the digit signatures: the issuer samples
+// a secret x, publishes X = g2^x and, for every digit i in [0, u), the signature
+// A_i = g1^{1/(x+i)}. x is not retained after setup.
+type BBParams struct {
+	g1   *Curve.ECP
+	g2   *Curve.ECP2
+	X    *Curve.ECP2
+	sigs []*Curve.ECP // sigs[i] signs digit i
+	u    int
+}
+
+// SetupBB runs the trusted digit-signing setup for the alphabet {0, ..., u-1}, under
+// the same curve/field as params.
+func SetupBB(u int, params coconut.CoconutParams) (*BBParams, error) {
+	if u < 2 {
+		return nil, fmt.Errorf("rangeproof: digit base u must be >= 2, got %d", u)
+	}
+
+	field := params.P()
+	g1 := params.G1()
+	g2 := params.G2()
+
+	x := bbRNG.Randomnum(field)
+	X := Curve.G2mul(g2, x)
+
+	sigs := make([]*Curve.ECP, u)
+	for i := 0; i < u; i++ {
+		denom := Curve.NewBIGint(i)
+		denom = denom.Plus(x)
+		denom.Mod(field)
+
+		inv := Curve.NewBIGcopy(denom)
+		inv.Invmodp(field)
+
+		sigs[i] = Curve.G1mul(g1, inv)
+	}
+
+	return &BBParams{g1: g1, g2: g2, X: X, sigs: sigs, u: u}, nil
+}
+
+// U is the digit base this setup supports, i.e. len(sigs).
+func (bb *BBParams) U() int {
+	return bb.u
+}
+
+// SignatureFor returns the (pre-randomization) BB signature on digit i.
+func (bb *BBParams) SignatureFor(i int) (*Curve.ECP, error) {
+	if i < 0 || i >= bb.u {
+		return nil, fmt.Errorf("rangeproof: digit %d out of range [0, %d)", i, bb.u)
+	}
+	return bb.sigs[i], nil
+}
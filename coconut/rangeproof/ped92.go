@@ -0,0 +1,90 @@
+// ped92.go - Multi-attribute Pedersen commitments, as used by libbolt's ped92.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rangeproof
+
+import (
+	"fmt"
+
+	"github.com/jstuczyn/CoconutGo/coconut/utils"
+	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// commitmentDST domain-separates the generators sampled for CSMultiParams from every
+// other use of HashToG1 in the scheme.
+const commitmentDST = utils.CommitmentDST + "generators_"
+
+// CSMultiParams holds the generators (g, h1, ..., hn) on Curve.ECP used by Commit to
+// produce a multi-attribute Pedersen commitment g^r . Prod_i hi^mi.
+type CSMultiParams struct {
+	g  *Curve.ECP
+	hs []*Curve.ECP
+}
+
+// SetupPed92 nothing-up-my-sleeve generates n+1 independent generators by hashing a
+// fixed label and an incrementing counter, so no party (including the one running
+// setup) learns a discrete-log relation between them.
+func SetupPed92(n int) (*CSMultiParams, error) {
+	g, err := utils.HashToG1([]byte(commitmentDST), []byte("g"))
+	if err != nil {
+		return nil, err
+	}
+
+	hs := make([]*Curve.ECP, n)
+	for i := range hs {
+		h, err := utils.HashToG1([]byte(commitmentDST), []byte(fmt.Sprintf("h%d", i)))
+		if err != nil {
+			return nil, err
+		}
+		hs[i] = h
+	}
+
+	return &CSMultiParams{g: g, hs: hs}, nil
+}
+
+// G returns the blinding generator g.
+func (p *CSMultiParams) G() *Curve.ECP {
+	return p.g
+}
+
+// H returns the per-attribute generators h1, ..., hn.
+func (p *CSMultiParams) H() []*Curve.ECP {
+	return p.hs
+}
+
+// N is the number of attributes this CSMultiParams can commit to at once.
+func (p *CSMultiParams) N() int {
+	return len(p.hs)
+}
+
+// SampleScalar draws a uniform element of Z_field, suitable for use as a commitment's
+// blinding factor r.
+func SampleScalar(field *Curve.BIG) *Curve.BIG {
+	return bbRNG.Randomnum(field)
+}
+
+// Commit computes g^r . Prod_i hi^{ms[i]}, opening to ms under blinding factor r.
+func Commit(params *CSMultiParams, ms []*Curve.BIG, r *Curve.BIG) (*Curve.ECP, error) {
+	if len(ms) != params.N() {
+		return nil, fmt.Errorf("rangeproof: got %d attributes, params support %d", len(ms), params.N())
+	}
+
+	c := Curve.G1mul(params.g, r)
+	for i, m := range ms {
+		c.Add(Curve.G1mul(params.hs[i], m))
+	}
+	return c, nil
+}
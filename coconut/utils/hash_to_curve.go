@@ -0,0 +1,249 @@
+// hash_to_curve.go - hash-to-field primitives (RFC 9380 section 5, conformant) and
+// HashToG1/HashStringToG1, a hash-to-curve function that is NOT RFC 9380 conformant.
+//
+// hhalpin/CoconutGo#chunk0-3 asked for RFC 9380's BLS12381G1_XMD:SHA-256_SSWU_RO_
+// hash-to-curve, specifically so this scheme's point hashing would be wire-compatible
+// with other BLS12-381 implementations. That is NOT delivered here and this file
+// should not be read as closing that request: map_to_curve below is still
+// BLS381.ECP_mapit, the pre-existing non-standard map, not the RFC's SSWU-plus-11-
+// isogeny map - see the HashToG1 doc comment for why, and what closing the gap for
+// real would need.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"errors"
+
+	"github.com/jstuczyn/amcl/version3/go/amcl"
+	"github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// Package-level domain-separation tags. Every distinct use of a hash ends up feeding
+// a different algebraic context (a signature, a commitment opening, a Fiat-Shamir
+// challenge), so each gets its own DST per RFC 9380 section 3.1 - reusing one DST
+// across contexts would let a value computed for one be replayed as if it were
+// computed for another.
+//
+// These suites are deliberately NOT named "..._SSWU_RO_": that RFC 9380 suite name is
+// reserved for implementations whose map_to_curve step is the RFC's own SSWU-plus-11-
+// isogeny map for G1 (BLS12381G1_XMD:SHA-256_SSWU_RO_), which is wire-compatible with
+// other conformant implementations.
+// HashToG1 below follows the RFC's hash_to_field/randomized-oracle composition
+// ("_RO_") but maps field elements to curve points via BLS381.ECP_mapit, so it is not
+// interoperable with an RFC 9380 SSWU implementation - see the HashToG1 doc comment.
+const (
+	// SigDST domain-separates hashing attributes for Coconut signing.
+	SigDST = "COCONUT-V01-CS02-with-BLS12381G1_XMD:SHA-256_RO_"
+	// CommitmentDST domain-separates hashing values used as Pedersen commitment openings.
+	CommitmentDST = "COCONUT-V01-CS02-with-BLS12381G1_XMD:SHA-256_RO_commitment_"
+	// ChallengeDST domain-separates Fiat-Shamir challenges in the show/request proofs.
+	ChallengeDST = "COCONUT-V01-CS02-with-BLS12381G1_XMD:SHA-256_RO_challenge_"
+)
+
+// hashToFieldL is ceil((ceil(log2(p)) + k) / 8) for BLS12-381 with a 128-bit security
+// margin (RFC 9380 section 5.3): the number of bytes expanded per field element so
+// that reducing them mod p introduces only negligible bias.
+const hashToFieldL = 64
+
+const (
+	sha256BlockBytes  = 64 // SHA-256 input block size (s_in_bytes in the RFC)
+	sha256OutputBytes = 32 // SHA-256 digest size (b_in_bytes in the RFC)
+)
+
+// expandMessageXMD implements expand_message_xmd from RFC 9380 section 5.4.1,
+// instantiated with SHA-256, producing lenInBytes pseudorandom bytes from msg that
+// are uniquely bound to dst.
+func expandMessageXMD(msg, dst []byte, lenInBytes int) ([]byte, error) {
+	if len(dst) > 255 {
+		return nil, errors.New("utils: dst too long for expand_message_xmd")
+	}
+	ell := (lenInBytes + sha256OutputBytes - 1) / sha256OutputBytes
+	if ell > 255 {
+		return nil, errors.New("utils: requested length too long for expand_message_xmd")
+	}
+
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+	lenInBytesStr := []byte{byte(lenInBytes >> 8), byte(lenInBytes)}
+
+	msgPrime := make([]byte, 0, sha256BlockBytes+len(msg)+len(lenInBytesStr)+1+len(dstPrime))
+	msgPrime = append(msgPrime, make([]byte, sha256BlockBytes)...) // Z_pad
+	msgPrime = append(msgPrime, msg...)
+	msgPrime = append(msgPrime, lenInBytesStr...)
+	msgPrime = append(msgPrime, 0)
+	msgPrime = append(msgPrime, dstPrime...)
+
+	b0 := sha256Sum(msgPrime)
+
+	b := make([][]byte, ell+1)
+	b[1] = sha256Sum(append(append(append([]byte{}, b0...), 1), dstPrime...))
+
+	for i := 2; i <= ell; i++ {
+		strxor := make([]byte, sha256OutputBytes)
+		for j := range strxor {
+			strxor[j] = b0[j] ^ b[i-1][j]
+		}
+		b[i] = sha256Sum(append(append(strxor, byte(i)), dstPrime...))
+	}
+
+	uniformBytes := make([]byte, 0, ell*sha256OutputBytes)
+	for i := 1; i <= ell; i++ {
+		uniformBytes = append(uniformBytes, b[i]...)
+	}
+	return uniformBytes[:lenInBytes], nil
+}
+
+func sha256Sum(data []byte) []byte {
+	h := amcl.NewHASH256()
+	h.Process_array(data)
+	return h.Hash()
+}
+
+// scalarFieldOrder returns the BLS12-381 scalar field order r, i.e. the same modulus
+// every coconut.Params.P() exposes, so a freshly hashed scalar can be reduced without
+// the caller having to thread params through every hashing call site.
+func scalarFieldOrder() *BLS381.BIG {
+	return BLS381.NewBIGints(BLS381.CURVE_Order)
+}
+
+// baseFieldOrder returns p, the BLS12-381 base field modulus that G1 point coordinates
+// live in. This is a different, larger modulus than scalarFieldOrder's r (the order of
+// the G1/G2 subgroups): hash_to_field must target whichever field the thing being
+// produced actually lives in, so HashToScalar reduces into r while HashToG1's
+// hash_to_field step below must reduce into p instead.
+//
+// BLOCKING, NOT YET CONFIRMED: BLS381.Modulus is assumed to be the ROM constant
+// array for p, mirroring how scalarFieldOrder above reads BLS381.CURVE_Order for r -
+// every amcl curve package this author has seen exposes both alongside each other,
+// but this environment has no copy of the real github.com/jstuczyn/amcl source to
+// check the name against, so this is an assumption, not a confirmed fact. If
+// BLS381.Modulus isn't the right name (or isn't exported), this package fails to
+// compile. Whoever has the real amcl/BLS381 source must confirm it before merging.
+func baseFieldOrder() *BLS381.BIG {
+	return BLS381.NewBIGints(BLS381.Modulus)
+}
+
+// hashToField implements hash_to_field from RFC 9380 section 5.2, generically over
+// whichever modulus the caller is targeting: it expands msg to count*L uniform bytes
+// under dst and reduces each L-byte chunk modulo m. Because each chunk is at least
+// 128 bits wider than m (hashToFieldL is sized for the larger of the two moduli this
+// package uses, baseFieldOrder's p), the reduction bias is negligible for either modulus -
+// unlike the old hashString+FromBytes pipeline, which reduced nothing at all.
+func hashToField(msg, dst []byte, count int, m *BLS381.BIG) ([]*BLS381.BIG, error) {
+	uniformBytes, err := expandMessageXMD(msg, dst, count*hashToFieldL)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*BLS381.BIG, count)
+	for i := 0; i < count; i++ {
+		out[i] = wideBytesMod(uniformBytes[i*hashToFieldL:(i+1)*hashToFieldL], m)
+	}
+	return out, nil
+}
+
+// wideBytesMod reduces a big-endian integer wider than BLS381.MODBYTES modulo m, by
+// splitting it into two halves and recombining them as hi*2^(8*half) + lo mod m.
+func wideBytesMod(b []byte, m *BLS381.BIG) *BLS381.BIG {
+	half := len(b) / 2
+	// FromBytes always reads exactly MODBYTES bytes, so each half is left-padded to
+	// that width first.
+	hiBuf := make([]byte, int(BLS381.MODBYTES))
+	copy(hiBuf[len(hiBuf)-half:], b[:half])
+	loBuf := make([]byte, int(BLS381.MODBYTES))
+	copy(loBuf[len(loBuf)-(len(b)-half):], b[half:])
+	hi := BLS381.FromBytes(hiBuf)
+	lo := BLS381.FromBytes(loBuf)
+	hi.Mod(m)
+	lo.Mod(m)
+
+	shift := BLS381.NewBIGint(1)
+	two := BLS381.NewBIGint(2)
+	for i := 0; i < half*8; i++ {
+		shift = BLS381.Modmul(shift, two, m)
+	}
+
+	result := BLS381.Modmul(hi, shift, m)
+	result = result.Plus(lo)
+	result.Mod(m)
+	return result
+}
+
+// bigToBytes serializes a BIG into a MODBYTES-length big-endian buffer, the inverse
+// of BLS381.FromBytes.
+func bigToBytes(b *BLS381.BIG) []byte {
+	buf := make([]byte, int(BLS381.MODBYTES))
+	b.ToBytes(buf)
+	return buf
+}
+
+// HashToScalar derives a single uniform scalar in Z_r from msg under dst. It replaces
+// HashStringToBig's old hashString+FromBytes pipeline, which truncated a single SHA
+// digest to MODBYTES and returned it unreduced - a value that can exceed r and that,
+// even once reduced, is not uniform over Z_r.
+func HashToScalar(dst, msg []byte) (*BLS381.BIG, error) {
+	scalars, err := hashToField(msg, dst, 1, scalarFieldOrder())
+	if err != nil {
+		return nil, err
+	}
+	return scalars[0], nil
+}
+
+// HashToG1 hashes msg to a point on G1 under dst. It is NOT an RFC 9380
+// BLS12381G1_XMD:SHA-256_SSWU_RO_ implementation and its output is NOT interoperable
+// with one - hhalpin/CoconutGo#chunk0-3 asked for that interoperability and this
+// function still does not provide it. Do not close, or treat as closed, that request
+// on account of this function.
+//
+// What it does do: follow the randomized-oracle shape of RFC 9380's hash_to_curve
+// (section 3) - hash_to_field to two independent, uniform field elements in GF(p),
+// map each to a curve point, and add the results together, so the overall map has no
+// detectable structure an attacker could exploit. But the field-element-to-curve step
+// is BLS381.ECP_mapit, the pre-existing non-constant-time map this codebase already
+// relied on elsewhere, not RFC 9380's SSWU-plus-11-isogeny map_to_curve. That map
+// needs the isogenous curve E': y^2 = x^3 + A'x + B' and the rational maps back to
+// G1's curve E: y^2 = x^3 + 4 (four polynomials of degree up to 15, ~50 field
+// elements total) from RFC 9380 section 8.8.1 - constants this environment has no way
+// to obtain or check: there's no network access to fetch the RFC or a reference
+// implementation to diff against, and no vendored copy in this tree either. Hand-
+// transcribing ~50 381-bit constants from memory with no way to verify even one of
+// them risks shipping silently-wrong curve arithmetic under an "RFC 9380 compliant"
+// label, which is a worse outcome than an honestly non-standard map - so this still
+// isn't done.
+//
+// What HAS changed from the previous revision: hash_to_field below now reduces into
+// baseFieldOrder's p (the field G1 coordinates actually live in), not
+// scalarFieldOrder's r as it incorrectly did before - hashToFieldL=64 was already
+// sized for p, so reducing mod r was an internal inconsistency on top of the
+// map_to_curve gap. Fixing that narrows, but does not close, the gap to a conformant
+// implementation: the uniform field elements fed into ECP_mapit are now at least in
+// the right field. Closing the gap for real needs either a transcription of the
+// isogeny constants checked against a vendored copy of RFC 9380 appendix J (its
+// J.9.1 test vectors, specifically) rather than memory, done somewhere that vendored
+// copy is available, or an explicit decision from whoever owns this backlog to
+// re-scope hhalpin/CoconutGo#chunk0-3 so it no longer asks for interoperability this
+// environment cannot safely deliver.
+func HashToG1(dst, msg []byte) (*BLS381.ECP, error) {
+	u, err := hashToField(msg, dst, 2, baseFieldOrder())
+	if err != nil {
+		return nil, err
+	}
+
+	q0 := BLS381.ECP_mapit(bigToBytes(u[0]))
+	q1 := BLS381.ECP_mapit(bigToBytes(u[1]))
+	q0.Add(q1)
+	return q0, nil
+}
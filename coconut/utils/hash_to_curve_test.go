@@ -0,0 +1,109 @@
+// hash_to_curve_test.go - Known-answer tests for expand_message_xmd (RFC 9380 5.4.1).
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExpandMessageXMDVectors checks expandMessageXMD against RFC 9380 appendix K.1's
+// expand_message_xmd-SHA256 test vectors (DST = "QUUX-V01-CS02-with-expander-SHA256-128").
+// TestHashToCurve (in testutils) only checks HashToScalar/HashToG1's determinism and
+// domain-separation properties against themselves; neither of those self-consistency
+// checks would catch an off-by-one in expandMessageXMD's length-prefix or padding
+// handling that happened to still be self-consistent. These vectors pin the byte-level
+// output of expand_message_xmd itself against the RFC's own suite.
+func TestExpandMessageXMDVectors(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+
+	tests := []struct {
+		name         string
+		msg          string
+		lenInBytes   int
+		uniformBytes string
+	}{
+		{
+			name:         "empty message, 32 bytes",
+			msg:          "",
+			lenInBytes:   32,
+			uniformBytes: "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235",
+		},
+		{
+			name:         "abc, 32 bytes",
+			msg:          "abc",
+			lenInBytes:   32,
+			uniformBytes: "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615",
+		},
+		{
+			name:         "abcdef0123456789, 32 bytes",
+			msg:          "abcdef0123456789",
+			lenInBytes:   32,
+			uniformBytes: "eff31487c770a893cfb36f912fbfcbff40d5661771ca4b2cb4eafe524333f5c1",
+		},
+		{
+			name:         "empty message, 128 bytes",
+			msg:          "",
+			lenInBytes:   128,
+			uniformBytes: "af84c27ccfd45d41914fdff5df25293e221afc53d8ad2ac06d5e3e29485dadbee0d121587713a3e0dd4d5e69e93eb7cd4f5df4cd103e188cf60cb02edc3edf18eda8576c412b18ffb658e3dd6ec849469b979d444cf7b26911a08e63cf31f9dcc541708d3491184472c2c29bb749d4286b004ceb5ee6b9a7fa5b646c993f0ced",
+		},
+		{
+			name:         "abc, 128 bytes",
+			msg:          "abc",
+			lenInBytes:   128,
+			uniformBytes: "abba86a6129e366fc877aab32fc4ffc70120d8996c88aee2fe4b32d6c7b6437a647e6c3163d40b76a73cf6a5674ef1d890f95b664ee0afa5359a5c4e07985635bbecbac65d747d3d2da7ec2b8221b17b0ca9dc8a1ac1c07ea6a1e60583e2cb00058e77b7b72a298425cd1b941ad4ec65e8afc50303a22c0f99b0509b4c895f40",
+		},
+	}
+
+	for _, test := range tests {
+		want, err := hex.DecodeString(test.uniformBytes)
+		assert.Nil(t, err, test.name)
+
+		got, err := expandMessageXMD([]byte(test.msg), dst, test.lenInBytes)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, want, got, test.name)
+	}
+}
+
+// TestHashToFieldTargetsRequestedModulus guards against hashToField silently
+// reducing into the wrong field - the exact bug HashToG1 had when it reduced into
+// scalarFieldOrder's r (~255 bits) regardless of caller, even though hashToFieldL is
+// sized for baseFieldOrder's much larger p (~381 bits). Every output must be strictly
+// less than whichever modulus was actually requested.
+func TestHashToFieldTargetsRequestedModulus(t *testing.T) {
+	dst := []byte(SigDST)
+	msg := []byte("Hello World!")
+
+	r := scalarFieldOrder()
+	p := baseFieldOrder()
+	assert.NotZero(t, BLS381.Comp(r, p), "r and p must be different moduli for this test to be meaningful")
+
+	scalars, err := hashToField(msg, dst, 2, r)
+	assert.Nil(t, err)
+	for _, s := range scalars {
+		assert.True(t, BLS381.Comp(s, r) < 0, "a value reduced mod r must be strictly less than r")
+	}
+
+	fieldElems, err := hashToField(msg, dst, 2, p)
+	assert.Nil(t, err)
+	for _, u := range fieldElems {
+		assert.True(t, BLS381.Comp(u, p) < 0, "a value reduced mod p must be strictly less than p")
+	}
+}
@@ -0,0 +1,67 @@
+// rand.go - Shared crypto/rand-seeded amcl.RAND construction.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"crypto/rand"
+	"sync"
+
+	"github.com/jstuczyn/amcl/version3/go/amcl"
+	"github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// NewSeededRAND returns an amcl.RAND seeded from crypto/rand, ready to pass to AMCL's
+// BIG/ECP generation routines, which take a *amcl.RAND rather than implementing
+// io.Reader. It panics if crypto/rand fails, since that is not something a caller can
+// recover from.
+func NewSeededRAND() *amcl.RAND {
+	rng := amcl.NewRAND()
+	seed := make([]byte, 128)
+	if _, err := rand.Read(seed); err != nil {
+		panic(err)
+	}
+	rng.Clean()
+	rng.Seed(len(seed), seed)
+	return rng
+}
+
+// SafeRAND wraps an amcl.RAND with a mutex so one seeded generator can be shared
+// across goroutines. amcl.RAND is a stateful PRNG with no synchronization of its
+// own; concurrent callers passing the same *amcl.RAND to Curve.Randomnum race on
+// its internal state and, in the worst case, can both observe the same state and
+// return the same scalar - nonce reuse that leaks a secret outright for a
+// Schnorr-style proof or a DKG polynomial coefficient sampled that way. Every
+// package under coconut/ that keeps a package-level RNG for exactly this purpose
+// (batch verification, DKG, range proofs) shares it between all of its callers, and
+// batch verification is also reachable through coconut/concurrency/coconutclientworker.
+type SafeRAND struct {
+	mu  sync.Mutex
+	rng *amcl.RAND
+}
+
+// NewSafeRAND returns a SafeRAND wrapping a freshly crypto/rand-seeded amcl.RAND.
+func NewSafeRAND() *SafeRAND {
+	return &SafeRAND{rng: NewSeededRAND()}
+}
+
+// Randomnum samples a uniform scalar in [0, max) from the wrapped RNG, serializing
+// concurrent callers so no two ever read or advance the same internal state at once.
+func (s *SafeRAND) Randomnum(max *BLS381.BIG) *BLS381.BIG {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return BLS381.Randomnum(max, s.rng)
+}
@@ -0,0 +1,117 @@
+// transcript.go - A Merlin-style Fiat-Shamir transcript for the Coconut proofs.
+// Copyright (C) 2018  Jedrzej Stuczynski.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package transcript gives every Fiat-Shamir challenge in the scheme a single,
+// canonical construction, modelled on Merlin's STROBE-based transcripts but built on
+// the hash_to_field primitives already added to coconut/utils: every appended value is
+// prefixed with a domain-separation label and a big-endian length before being folded
+// in, so the encoding is injective and two different sequences of appends can never
+// collide onto the same challenge input. This replaces hashing a stringified,
+// ad-hoc concatenation of group elements, which had neither property.
+//
+// So far only coconut/rangeproof's Fiat-Shamir challenge has been migrated onto this
+// package. The blind-signature-request and credential-show proofs this was originally
+// meant to replace ad-hoc hashing in do not exist in this tree - this snapshot only
+// ever contained coconut/utils/utils.go and testutils/scheme.go plus whatever later
+// requests in this backlog added, never the show/request protocol itself - so there is
+// nothing under coconut/ to rewire onto Transcript yet. Whoever adds that protocol
+// should build its challenges through a Transcript from the start rather than through
+// string concatenation.
+package transcript
+
+import (
+	"encoding/binary"
+
+	"github.com/jstuczyn/CoconutGo/coconut/utils"
+	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
+)
+
+// transcriptDST is the fixed domain separator for every challenge derived from a
+// Transcript; the transcript's own label (passed to New) further separates distinct
+// protocols from one another within that.
+const transcriptDST = "COCONUT-V01-CS02-transcript_"
+
+// Transcript accumulates domain-separated, length-prefixed data and derives uniform
+// challenge scalars from it. It is not safe for concurrent use.
+type Transcript struct {
+	label string
+	data  []byte
+}
+
+// New starts a fresh transcript for a protocol identified by label, e.g.
+// "coconut-show" or "coconut-request".
+func New(label string) *Transcript {
+	return &Transcript{label: label}
+}
+
+// appendFramed appends label and data to the transcript, each prefixed with its own
+// big-endian uint32 length, so that no sequence of appends can be confused with a
+// different one (e.g. AppendBytes("a", "bc") can never collide with
+// AppendBytes("ab", "c")).
+func (t *Transcript) appendFramed(label string, data []byte) {
+	var lenBuf [4]byte
+
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(label)))
+	t.data = append(t.data, lenBuf[:]...)
+	t.data = append(t.data, label...)
+
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(data)))
+	t.data = append(t.data, lenBuf[:]...)
+	t.data = append(t.data, data...)
+}
+
+// AppendBytes appends an arbitrary byte string under label.
+func (t *Transcript) AppendBytes(label string, data []byte) {
+	t.appendFramed(label, data)
+}
+
+// AppendScalar appends a field element under label.
+func (t *Transcript) AppendScalar(label string, s *Curve.BIG) {
+	buf := make([]byte, int(Curve.MODBYTES))
+	s.ToBytes(buf)
+	t.appendFramed(label, buf)
+}
+
+// AppendG1 appends a G1 point under label.
+func (t *Transcript) AppendG1(label string, p *Curve.ECP) {
+	buf := make([]byte, int(Curve.MODBYTES)+1)
+	p.ToBytes(buf, true)
+	t.appendFramed(label, buf)
+}
+
+// AppendG2 appends a G2 point under label.
+func (t *Transcript) AppendG2(label string, p *Curve.ECP2) {
+	buf := make([]byte, 4*int(Curve.MODBYTES))
+	p.ToBytes(buf)
+	t.appendFramed(label, buf)
+}
+
+// ChallengeScalar derives a uniform scalar in Z_r from everything appended to the
+// transcript so far, domain-separated by both label and this transcript's own label.
+// Deriving a challenge does not consume or reset the transcript's state - later
+// challenges (or further appends) still see everything appended up to that point.
+func (t *Transcript) ChallengeScalar(label string) (*Curve.BIG, error) {
+	msg := make([]byte, len(t.data))
+	copy(msg, t.data)
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], uint32(len(label)))
+	msg = append(msg, lenBuf[:]...)
+	msg = append(msg, label...)
+
+	dst := transcriptDST + t.label
+	return utils.HashToScalar([]byte(dst), msg)
+}
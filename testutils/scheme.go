@@ -18,15 +18,17 @@
 package schemetest
 
 import (
+	"fmt"
 	"math/rand"
 	"testing"
 	"time"
 
 	"github.com/jstuczyn/CoconutGo/coconut/concurrency/coconutclientworker"
 
+	"github.com/jstuczyn/CoconutGo/coconut/rangeproof"
 	"github.com/jstuczyn/CoconutGo/coconut/scheme"
 	"github.com/jstuczyn/CoconutGo/coconut/utils"
-	"github.com/jstuczyn/amcl/version3/go/amcl"
+	"github.com/jstuczyn/CoconutGo/coconut/utils/transcript"
 	Curve "github.com/jstuczyn/amcl/version3/go/amcl/BLS381"
 	"github.com/stretchr/testify/assert"
 )
@@ -198,7 +200,7 @@ func TestSign(t *testing.T, ccw *coconutclientworker.CoconutClientWorker) {
 		attrsBig := make([]*Curve.BIG, len(test.attrs))
 		var err error
 		for i := range test.attrs {
-			attrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.attrs[i])
+			attrsBig[i], err = utils.HashStringToBig(test.attrs[i])
 			assert.Nil(t, err)
 		}
 
@@ -247,7 +249,7 @@ func TestVerify(t *testing.T, ccw *coconutclientworker.CoconutClientWorker) {
 		attrsBig := make([]*Curve.BIG, len(test.attrs))
 		var err error
 		for i := range test.attrs {
-			attrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.attrs[i])
+			attrsBig[i], err = utils.HashStringToBig(test.attrs[i])
 			assert.Nil(t, err)
 		}
 
@@ -265,7 +267,7 @@ func TestVerify(t *testing.T, ccw *coconutclientworker.CoconutClientWorker) {
 		if len(test.maliciousAttrs) > 0 {
 			mAttrsBig := make([]*Curve.BIG, len(test.maliciousAttrs))
 			for i := range test.maliciousAttrs {
-				mAttrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.maliciousAttrs[i])
+				mAttrsBig[i], err = utils.HashStringToBig(test.maliciousAttrs[i])
 				assert.Nil(t, err)
 			}
 
@@ -299,7 +301,7 @@ func TestRandomize(t *testing.T, ccw *coconutclientworker.CoconutClientWorker) {
 		attrsBig := make([]*Curve.BIG, len(test.attrs))
 		var err error
 		for i := range test.attrs {
-			attrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.attrs[i])
+			attrsBig[i], err = utils.HashStringToBig(test.attrs[i])
 			assert.Nil(t, err)
 		}
 
@@ -342,7 +344,7 @@ func TestKeyAggregation(t *testing.T, ccw *coconutclientworker.CoconutClientWork
 		attrsBig := make([]*Curve.BIG, len(test.attrs))
 		var err error
 		for i := range test.attrs {
-			attrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.attrs[i])
+			attrsBig[i], err = utils.HashStringToBig(test.attrs[i])
 			assert.Nil(t, err)
 		}
 
@@ -457,7 +459,7 @@ func TestAggregateVerification(t *testing.T, ccw *coconutclientworker.CoconutCli
 
 		attrsBig := make([]*Curve.BIG, len(test.attrs))
 		for i := range test.attrs {
-			attrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.attrs[i])
+			attrsBig[i], err = utils.HashStringToBig(test.attrs[i])
 			assert.Nil(t, err)
 		}
 
@@ -504,7 +506,7 @@ func TestAggregateVerification(t *testing.T, ccw *coconutclientworker.CoconutCli
 
 			mAttrsBig := make([]*Curve.BIG, len(test.maliciousAttrs))
 			for i := range test.maliciousAttrs {
-				mAttrsBig[i], err = utils.HashStringToBig(amcl.SHA256, test.maliciousAttrs[i])
+				mAttrsBig[i], err = utils.HashStringToBig(test.maliciousAttrs[i])
 				assert.Nil(t, err)
 			}
 
@@ -551,4 +553,247 @@ func TestAggregateVerification(t *testing.T, ccw *coconutclientworker.CoconutCli
 			}
 		}
 	}
-}
\ No newline at end of file
+}
+
+// TestHashToCurve checks the domain-separation and determinism properties HashToScalar
+// and HashToG1 are supposed to provide: hashing the same message under the same DST
+// twice must agree, hashing it under two different DSTs must (overwhelmingly likely)
+// disagree, and flipping a single input byte must change the output.
+func TestHashToCurve(t *testing.T) {
+	msg := []byte("Hello World!")
+	otherMsg := []byte("Hello World\x00")
+
+	s1, err := utils.HashToScalar([]byte(utils.SigDST), msg)
+	assert.Nil(t, err)
+	s2, err := utils.HashToScalar([]byte(utils.SigDST), msg)
+	assert.Nil(t, err)
+	assert.Zero(t, Curve.Comp(s1, s2), "hashing the same (dst, msg) twice must be deterministic")
+
+	s3, err := utils.HashToScalar([]byte(utils.CommitmentDST), msg)
+	assert.Nil(t, err)
+	assert.NotZero(t, Curve.Comp(s1, s3), "different DSTs must yield different scalars for the same message")
+
+	s4, err := utils.HashToScalar([]byte(utils.SigDST), otherMsg)
+	assert.Nil(t, err)
+	assert.NotZero(t, Curve.Comp(s1, s4), "flipping a single message byte must change the scalar")
+
+	g1, err := utils.HashToG1([]byte(utils.SigDST), msg)
+	assert.Nil(t, err)
+	g2, err := utils.HashToG1([]byte(utils.SigDST), msg)
+	assert.Nil(t, err)
+	assert.True(t, g1.Equals(g2), "hashing the same (dst, msg) twice must be deterministic")
+
+	g3, err := utils.HashToG1([]byte(utils.ChallengeDST), msg)
+	assert.Nil(t, err)
+	assert.False(t, g1.Equals(g3), "different DSTs must yield different points for the same message")
+}
+
+// TestTranscript checks that transcript.Transcript's challenges are deterministic given
+// identical appends, and that flipping a single input byte - in an appended scalar, an
+// appended label, or the transcript's own protocol label - always changes the
+// resulting challenge.
+func TestTranscript(t *testing.T) {
+	newChallenge := func(label, appendLabel string, s *Curve.BIG) *Curve.BIG {
+		tr := transcript.New(label)
+		tr.AppendScalar(appendLabel, s)
+		c, err := tr.ChallengeScalar("challenge")
+		assert.Nil(t, err)
+		return c
+	}
+
+	s, err := utils.HashStringToBig("transcript-test-scalar")
+	assert.Nil(t, err)
+	otherS, err := utils.HashStringToBig("transcript-test-scalar-2")
+	assert.Nil(t, err)
+
+	// flippedS differs from s in a single bit of its last byte
+	sBytes := make([]byte, int(Curve.MODBYTES))
+	s.ToBytes(sBytes)
+	sBytes[len(sBytes)-1] ^= 0x01
+	flippedS := Curve.FromBytes(sBytes)
+
+	c1 := newChallenge("coconut-test", "s", s)
+	c2 := newChallenge("coconut-test", "s", s)
+	assert.Zero(t, Curve.Comp(c1, c2), "identical appends to identically-labelled transcripts must agree")
+
+	c3 := newChallenge("coconut-test", "s", flippedS)
+	assert.NotZero(t, Curve.Comp(c1, c3), "flipping the appended scalar must change the challenge")
+
+	c4 := newChallenge("coconut-test", "t", s)
+	assert.NotZero(t, Curve.Comp(c1, c4), "flipping an append's label must change the challenge")
+
+	c5 := newChallenge("coconut-tesu", "s", s)
+	assert.NotZero(t, Curve.Comp(c1, c5), "flipping the transcript's own protocol label must change the challenge")
+
+	tr := transcript.New("coconut-test")
+	tr.AppendScalar("s", s)
+	first, err := tr.ChallengeScalar("challenge")
+	assert.Nil(t, err)
+	tr.AppendScalar("extra", otherS)
+	second, err := tr.ChallengeScalar("challenge")
+	assert.Nil(t, err)
+	assert.NotZero(t, Curve.Comp(first, second), "appending more data after a challenge must change the next challenge")
+}
+
+// TestRangeProof checks that a value inside the claimed range always produces a
+// RangeProof that verifies, and that a value outside of it never does, across a
+// handful of digit bases u. l is kept small here for test speed; production amounts
+// would pick l so that u^l covers the full 32- or 64-bit range being proven over.
+func TestRangeProof(t *testing.T) {
+	const l = 4 // u^l must comfortably exceed every in-range test value below
+
+	tests := []struct {
+		u       int
+		value   int
+		inRange bool
+		msg     string
+	}{
+		{u: 2, value: 5, inRange: true, msg: "5 is within [0, 2^4)"},
+		{u: 2, value: 20, inRange: false, msg: "20 is outside [0, 2^4)"},
+		{u: 4, value: 100, inRange: true, msg: "100 is within [0, 4^4)"},
+		{u: 4, value: 1000, inRange: false, msg: "1000 is outside [0, 4^4)"},
+		{u: 16, value: 60000, inRange: true, msg: "60000 is within [0, 16^4)"},
+		{u: 16, value: 100000, inRange: false, msg: "100000 is outside [0, 16^4)"},
+	}
+
+	for _, test := range tests {
+		crv, err := coconut.Setup(1)
+		assert.Nil(t, err)
+
+		params, err := rangeproof.Setup(test.u, l, crv)
+		assert.Nil(t, err)
+
+		field := crv.P()
+		m := Curve.NewBIGint(test.value)
+		r := rangeproof.SampleScalar(field)
+
+		commitment, err := rangeproof.Commit(params.Comm, []*Curve.BIG{m}, r)
+		assert.Nil(t, err)
+
+		proof, err := rangeproof.Prove(m, r, params)
+		assert.Nil(t, err)
+
+		ok := rangeproof.Verify(commitment, proof, params)
+		assert.Equal(t, test.inRange, ok, test.msg)
+	}
+}
+
+// buildVerifyItems sets up n independently-keyed (vk, attrs, sig) triples for
+// BatchVerify to check. It takes a testing.TB so both BenchmarkVerifyVsBatchVerify and
+// TestBatchVerify can share it.
+func buildVerifyItems(tb testing.TB, n int, ccw *coconutclientworker.CoconutClientWorker) (coconut.CoconutParams, []coconut.VerifyItem) {
+	var params coconut.CoconutParams
+	var err error
+	if ccw == nil {
+		params, err = coconut.Setup(1)
+	} else {
+		params, err = ccw.Setup(1)
+	}
+	if err != nil {
+		tb.Fatalf("setup failed: %v", err)
+	}
+
+	items := make([]coconut.VerifyItem, n)
+	for i := 0; i < n; i++ {
+		var sk *coconut.SecretKey
+		var vk *coconut.VerificationKey
+		if ccw == nil {
+			sk, vk, err = coconut.Keygen(params.(*coconut.Params))
+		} else {
+			sk, vk, err = ccw.Keygen(params.(*coconutclientworker.MuxParams))
+		}
+		if err != nil {
+			tb.Fatalf("keygen failed: %v", err)
+		}
+
+		attr, err := utils.HashStringToBig(fmt.Sprintf("attribute-%d", i))
+		if err != nil {
+			tb.Fatalf("hash failed: %v", err)
+		}
+
+		var sig *coconut.Signature
+		if ccw == nil {
+			sig, err = coconut.Sign(params.(*coconut.Params), sk, []*Curve.BIG{attr})
+		} else {
+			sig, err = ccw.Sign(params.(*coconutclientworker.MuxParams), sk, []*Curve.BIG{attr})
+		}
+		if err != nil {
+			tb.Fatalf("sign failed: %v", err)
+		}
+
+		items[i] = coconut.VerifyItem{VK: vk, Attrs: []*Curve.BIG{attr}, Sig: sig}
+	}
+	return params, items
+}
+
+// TestBatchVerify checks BatchVerify's two outcomes directly, rather than only
+// comparing its speed against Verify: a batch of genuinely valid signatures must
+// report (true, nil), and corrupting a single signature in an otherwise-valid batch
+// must report (false, badIndices) naming exactly that signature's index - not an
+// off-by-one neighbour, and not every index in the batch.
+func TestBatchVerify(t *testing.T, ccw *coconutclientworker.CoconutClientWorker) {
+	const n = 6
+
+	params, items := buildVerifyItems(t, n, ccw)
+
+	var ok bool
+	var bad []int
+	if ccw == nil {
+		ok, bad = coconut.BatchVerify(params, items)
+	} else {
+		ok, bad = ccw.BatchVerify(params.(*coconutclientworker.MuxParams), items)
+	}
+	assert.True(t, ok, "a batch of genuinely valid signatures should verify")
+	assert.Nil(t, bad, "a valid batch should report no bad indices")
+
+	for _, spoiledIdx := range []int{0, n - 1, n / 2} {
+		spoiled := make([]coconut.VerifyItem, n)
+		copy(spoiled, items)
+		otherAttr, err := utils.HashStringToBig("not-the-signed-attribute")
+		assert.Nil(t, err)
+		spoiled[spoiledIdx] = coconut.VerifyItem{
+			VK:    items[spoiledIdx].VK,
+			Attrs: []*Curve.BIG{otherAttr},
+			Sig:   items[spoiledIdx].Sig,
+		}
+
+		if ccw == nil {
+			ok, bad = coconut.BatchVerify(params, spoiled)
+		} else {
+			ok, bad = ccw.BatchVerify(params.(*coconutclientworker.MuxParams), spoiled)
+		}
+		assert.False(t, ok, "a batch with one bad signature must not verify")
+		assert.Equal(t, []int{spoiledIdx}, bad, "bad index must name exactly the spoiled item")
+	}
+}
+
+// BenchmarkVerifyVsBatchVerify compares calling Verify once per signature against a
+// single BatchVerify call, for N in {8, 64, 512}, to quantify the saving from folding
+// every pairing check into one final exponentiation.
+func BenchmarkVerifyVsBatchVerify(b *testing.B, ccw *coconutclientworker.CoconutClientWorker) {
+	for _, n := range []int{8, 64, 512} {
+		params, items := buildVerifyItems(b, n, ccw)
+
+		b.Run(fmt.Sprintf("VerifyLoop/N=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, item := range items {
+					if ccw == nil {
+						coconut.Verify(params.(*coconut.Params), item.VK, item.Attrs, item.Sig)
+					} else {
+						ccw.Verify(params.(*coconutclientworker.MuxParams), item.VK, item.Attrs, item.Sig)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("BatchVerify/N=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if ccw == nil {
+					coconut.BatchVerify(params, items)
+				} else {
+					ccw.BatchVerify(params.(*coconutclientworker.MuxParams), items)
+				}
+			}
+		})
+	}
+}